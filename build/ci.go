@@ -0,0 +1,657 @@
+//go:build none
+
+// This file is part of gnomo's release tooling, invoked as:
+//
+//	go run build/ci.go <command> [flags] [arguments]
+//
+// It exists so CI and release builds don't depend on ad-hoc shell scripts:
+// it knows how to build binaries with the right -ldflags, run the test
+// suite, and produce signed, reproducible release archives.
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const module = "github.com/tazmaan-defi/gnomo"
+
+// defaultReleaseTargets is the GOOS/GOARCH matrix "release" builds for when
+// -targets isn't given.
+const defaultReleaseTargets = "linux/amd64,linux/arm64,darwin/amd64,darwin/arm64,windows/amd64"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: go run build/ci.go <command> [flags] [arguments]")
+		fmt.Fprintln(os.Stderr, "commands: install, test, archive, release, importkeys")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "install":
+		err = doInstall(os.Args[2:])
+	case "test":
+		err = doTest(os.Args[2:])
+	case "archive":
+		err = doArchive(os.Args[2:])
+	case "release":
+		err = doRelease(os.Args[2:])
+	case "importkeys":
+		err = doImportKeys(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes name with args, streaming its output to our own stdout/stderr.
+// Under -n it only prints the command it would have run.
+func run(dryRun bool, name string, args ...string) error {
+	fmt.Println(">>>", name, strings.Join(args, " "))
+	if dryRun {
+		return nil
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runOutput(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitVersion returns the nearest tag describing HEAD, e.g. "v1.2.3" or
+// "v1.2.3-4-gdeadbee", falling back to "dev" outside a git checkout.
+func gitVersion() string {
+	if v, err := runOutput("git", "describe", "--tags", "--always", "--dirty"); err == nil {
+		return v
+	}
+	return "dev"
+}
+
+// gitCommit returns the full HEAD commit hash, falling back to "none".
+func gitCommit() string {
+	if c, err := runOutput("git", "log", "-n1", "--format=%H"); err == nil {
+		return c
+	}
+	return "none"
+}
+
+// gitCommitDate returns the ISO-8601 commit timestamp of HEAD, falling back
+// to "unknown".
+func gitCommitDate() string {
+	if d, err := runOutput("git", "log", "-n1", "--format=%cI"); err == nil {
+		return d
+	}
+	return "unknown"
+}
+
+// gitTreeState reports whether the working tree has uncommitted changes,
+// as "clean" or "dirty".
+func gitTreeState() string {
+	out, err := runOutput("git", "status", "--porcelain")
+	if err != nil || out == "" {
+		return "clean"
+	}
+	return "dirty"
+}
+
+// sourceDateEpochDate returns the build timestamp to stamp into main.date
+// for reproducible builds: SOURCE_DATE_EPOCH if set (the convention used by
+// reproducible-builds.org), otherwise HEAD's commit time.
+func sourceDateEpochDate() string {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		epoch, _ = runOutput("git", "log", "-n1", "--format=%ct")
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return gitCommitDate()
+	}
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+// buildLdflags assembles the -ldflags string that stamps build/version
+// metadata into cmd/gnomo's package-main variables.
+func buildLdflags() string {
+	var flags []string
+	flags = append(flags, fmt.Sprintf("-X main.version=%s", gitVersion()))
+	flags = append(flags, fmt.Sprintf("-X main.commit=%s", gitCommit()))
+	flags = append(flags, fmt.Sprintf("-X main.date=%s", gitCommitDate()))
+	return strings.Join(flags, " ")
+}
+
+// doInstall builds the given packages (cmd/gnomo by default) with the
+// release ldflags, optionally cross-compiling for -arch.
+func doInstall(cmdline []string) error {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	arch := fs.String("arch", "", "GOARCH to build for (defaults to the host arch)")
+	dryRun := fs.Bool("n", false, "print the commands that would run, without running them")
+	fs.Parse(cmdline)
+
+	packages := fs.Args()
+	if len(packages) == 0 {
+		packages = []string{"./cmd/gnomo"}
+	}
+
+	args := []string{"build", "-ldflags", buildLdflags()}
+	args = append(args, packages...)
+
+	env := os.Environ()
+	if *arch != "" {
+		env = append(env, "GOARCH="+*arch)
+	}
+
+	fmt.Println(">>> go", strings.Join(args, " "))
+	if *dryRun {
+		return nil
+	}
+	cmd := exec.Command("go", args...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// doTest runs the test suite, optionally with coverage and go vet.
+func doTest(cmdline []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	coverage := fs.Bool("coverage", false, "enable coverage reporting")
+	vet := fs.Bool("vet", false, "run go vet before the tests")
+	dryRun := fs.Bool("n", false, "print the commands that would run, without running them")
+	fs.Parse(cmdline)
+
+	packages := fs.Args()
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	if *vet {
+		args := append([]string{"vet"}, packages...)
+		if err := run(*dryRun, "go", args...); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"test"}
+	if *coverage {
+		args = append(args, "-cover")
+	}
+	args = append(args, packages...)
+	return run(*dryRun, "go", args...)
+}
+
+// doArchive builds cmd/gnomo for -arch, then packages the binary together
+// with LICENSE and README into a release archive under dist/, recording its
+// checksum and optionally GPG-signing it.
+func doArchive(cmdline []string) error {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	arch := fs.String("arch", "", "GOARCH to build for (defaults to the host arch)")
+	archiveType := fs.String("type", "tar", "archive format: tar or zip")
+	signer := fs.String("signer", "", "environment variable holding the base64 PGP key to sign the archive with")
+	upload := fs.String("upload", "", "destination to copy the finished archive to")
+	dryRun := fs.Bool("n", false, "print the commands that would run, without running them")
+	fs.Parse(cmdline)
+
+	if *archiveType != "tar" && *archiveType != "zip" {
+		return fmt.Errorf("unknown archive type %q (want tar or zip)", *archiveType)
+	}
+
+	installArgs := []string{"-arch", *arch}
+	if *dryRun {
+		installArgs = append(installArgs, "-n")
+	}
+	if err := doInstall(installArgs); err != nil {
+		return err
+	}
+
+	version := gitVersion()
+	goos, goarch := hostPlatform(*arch)
+	base := fmt.Sprintf("gnomo-%s-%s-%s", version, goos, goarch)
+
+	distDir := "dist"
+	if !*dryRun {
+		if err := os.MkdirAll(distDir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	binary := "gnomo"
+	if goos == "windows" {
+		binary += ".exe"
+	}
+	entries := existingEntries([]archiveEntry{
+		{srcPath: binary, name: binary, mode: 0o755},
+		{srcPath: "LICENSE", name: "LICENSE", mode: 0o644},
+		{srcPath: "README.md", name: "README.md", mode: 0o644},
+	})
+
+	var archivePath string
+	switch *archiveType {
+	case "tar":
+		archivePath = filepath.Join(distDir, base+".tar.gz")
+	case "zip":
+		archivePath = filepath.Join(distDir, base+".zip")
+	}
+
+	fmt.Printf(">>> package %s into %s\n", binary, archivePath)
+	if !*dryRun {
+		// Stamp a fixed mtime (the HEAD commit date) on every entry instead
+		// of the files' real mtimes, so the archive's bytes are reproducible
+		// across builds of the same commit.
+		mtime, err := time.Parse(time.RFC3339, gitCommitDate())
+		if err != nil {
+			mtime = time.Unix(0, 0).UTC()
+		}
+		switch *archiveType {
+		case "tar":
+			err = writeTarGz(archivePath, mtime, entries)
+		case "zip":
+			err = writeZip(archivePath, mtime, entries)
+		}
+		if err != nil {
+			return fmt.Errorf("packaging %s: %w", archivePath, err)
+		}
+		if err := writeChecksum(archivePath); err != nil {
+			return err
+		}
+	}
+
+	if *signer != "" {
+		if err := signFile(*dryRun, archivePath, *signer); err != nil {
+			return err
+		}
+	}
+
+	if *upload != "" {
+		if err := run(*dryRun, "cp", archivePath, *upload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostPlatform resolves the GOOS/GOARCH pair an archive is built for, using
+// the host's GOOS and the given GOARCH override (or the host's own arch).
+func hostPlatform(archOverride string) (goos, goarch string) {
+	goos = os.Getenv("GOOS")
+	if goos == "" {
+		goos, _ = runOutput("go", "env", "GOOS")
+	}
+	goarch = archOverride
+	if goarch == "" {
+		goarch, _ = runOutput("go", "env", "GOARCH")
+	}
+	return goos, goarch
+}
+
+// existingEntries filters entries down to those whose source file actually
+// exists, printing a notice for anything skipped. Optional files like
+// LICENSE or README.md shouldn't turn a missing file into a hard failure.
+func existingEntries(entries []archiveEntry) []archiveEntry {
+	var out []archiveEntry
+	for _, e := range entries {
+		if _, err := os.Stat(e.srcPath); err != nil {
+			fmt.Printf(">>> skipping %s (not found)\n", e.srcPath)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// doRelease cross-compiles cmd/gnomo for a matrix of GOOS/GOARCH targets
+// (linux/amd64, linux/arm64, darwin/amd64, darwin/arm64 and windows/amd64 by
+// default, overridable with -targets) and packages each into a reproducible
+// archive under dist/, alongside a top-level checksums.txt.
+//
+// Reproducibility means two builds from the same commit produce
+// byte-identical archives: -trimpath strips local filesystem paths,
+// -buildvcs=false stops the toolchain from embedding its own (potentially
+// dirty-tree-sensitive) VCS stamp since we inject one ourselves, and the
+// build timestamp comes from SOURCE_DATE_EPOCH rather than time.Now.
+func doRelease(cmdline []string) error {
+	fs := flag.NewFlagSet("release", flag.ExitOnError)
+	targetsFlag := fs.String("targets", defaultReleaseTargets, "comma-separated GOOS/GOARCH pairs, e.g. linux/amd64,darwin/arm64")
+	archiveType := fs.String("type", "tar", "archive format for non-Windows targets: tar or zip (Windows targets always use zip)")
+	signer := fs.String("signer", "", "environment variable holding the base64 PGP key to sign each archive with")
+	dryRun := fs.Bool("n", false, "print the commands that would run, without running them")
+	fs.Parse(cmdline)
+
+	version := gitVersion()
+	commit := gitCommit()
+	treeState := gitTreeState()
+	date := sourceDateEpochDate()
+
+	distDir := "dist"
+	if !*dryRun {
+		if err := os.MkdirAll(distDir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	mtime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		mtime = time.Unix(0, 0).UTC()
+	}
+
+	var archivePaths []string
+	for _, target := range strings.Split(*targetsFlag, ",") {
+		goos, goarch, err := splitTarget(target)
+		if err != nil {
+			return err
+		}
+
+		binary := "gnomo"
+		if goos == "windows" {
+			binary += ".exe"
+		}
+		buildDir := filepath.Join(distDir, fmt.Sprintf(".build-%s-%s", goos, goarch))
+		binaryPath := filepath.Join(buildDir, binary)
+
+		ldflags := releaseLdflags(version, commit, date, treeState)
+		args := []string{
+			"build", "-trimpath", "-buildvcs=false",
+			"-ldflags", ldflags,
+			"-o", binaryPath,
+			"./cmd/gnomo",
+		}
+		fmt.Printf(">>> GOOS=%s GOARCH=%s go %s\n", goos, goarch, strings.Join(args, " "))
+		if !*dryRun {
+			if err := os.MkdirAll(buildDir, 0o755); err != nil {
+				return err
+			}
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return err
+			}
+		}
+
+		at := *archiveType
+		if goos == "windows" {
+			at = "zip"
+		}
+		base := fmt.Sprintf("gnomo-%s-%s-%s", version, goos, goarch)
+		var archivePath string
+		switch at {
+		case "tar":
+			archivePath = filepath.Join(distDir, base+".tar.gz")
+		case "zip":
+			archivePath = filepath.Join(distDir, base+".zip")
+		default:
+			return fmt.Errorf("unknown archive type %q (want tar or zip)", at)
+		}
+
+		entries := existingEntries([]archiveEntry{
+			{srcPath: binaryPath, name: binary, mode: 0o755},
+			{srcPath: "LICENSE", name: "LICENSE", mode: 0o644},
+			{srcPath: "README.md", name: "README.md", mode: 0o644},
+		})
+
+		fmt.Printf(">>> package %s into %s\n", binary, archivePath)
+		if !*dryRun {
+			var err error
+			switch at {
+			case "tar":
+				err = writeTarGz(archivePath, mtime, entries)
+			case "zip":
+				err = writeZip(archivePath, mtime, entries)
+			}
+			if err != nil {
+				return fmt.Errorf("packaging %s: %w", archivePath, err)
+			}
+		}
+
+		if *signer != "" {
+			if err := signFile(*dryRun, archivePath, *signer); err != nil {
+				return err
+			}
+		}
+		archivePaths = append(archivePaths, archivePath)
+	}
+
+	checksumsPath := filepath.Join(distDir, "checksums.txt")
+	fmt.Println(">>> write", checksumsPath)
+	if *dryRun {
+		return nil
+	}
+	return writeChecksums(checksumsPath, archivePaths)
+}
+
+// splitTarget parses a "GOOS/GOARCH" target string.
+func splitTarget(target string) (goos, goarch string, err error) {
+	parts := strings.SplitN(strings.TrimSpace(target), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid target %q (want GOOS/GOARCH)", target)
+	}
+	return parts[0], parts[1], nil
+}
+
+// releaseLdflags assembles the -ldflags string for reproducible release
+// builds: stripped symbol tables plus the version, commit, date and
+// treeState the version subcommand reports (see internal/version).
+func releaseLdflags(version, commit, date, treeState string) string {
+	flags := []string{
+		"-s", "-w",
+		fmt.Sprintf("-X main.version=%s", version),
+		fmt.Sprintf("-X main.commit=%s", commit),
+		fmt.Sprintf("-X main.date=%s", date),
+		fmt.Sprintf("-X main.treeState=%s", treeState),
+	}
+	return strings.Join(flags, " ")
+}
+
+// archiveEntry is one file to place into a release archive.
+type archiveEntry struct {
+	srcPath string // file to read from disk
+	name    string // path to store it under inside the archive
+	mode    int64
+}
+
+// writeTarGz writes entries into a gzip-compressed tar archive at path,
+// stamping every entry with mtime so the archive's bytes are reproducible
+// across builds of the same commit.
+func writeTarGz(path string, mtime time.Time, entries []archiveEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		data, err := os.ReadFile(e.srcPath)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    e.mode,
+			Size:    int64(len(data)),
+			ModTime: mtime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeZip writes entries into a zip archive at path, stamping every entry
+// with mtime so the archive's bytes are reproducible across builds of the
+// same commit.
+func writeZip(path string, mtime time.Time, entries []archiveEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		data, err := os.ReadFile(e.srcPath)
+		if err != nil {
+			return err
+		}
+		fh := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: mtime}
+		fh.SetMode(os.FileMode(e.mode))
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeChecksums writes a sha256sum-compatible "<hash>  <path>" line for
+// each of paths into path.
+func writeChecksums(path string, paths []string) error {
+	var b strings.Builder
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(p))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// writeChecksum appends the archive's SHA256 checksum to SHA256SUMS next to
+// it, in the usual "<hash>  <filename>" sha256sum format.
+func writeChecksum(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	sumsPath := filepath.Join(filepath.Dir(archivePath), "SHA256SUMS")
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(archivePath))
+
+	out, err := os.OpenFile(sumsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.WriteString(line)
+	return err
+}
+
+// signFile GPG-signs path with the key loaded from the environment variable
+// named by signerEnvVar, producing a detached path+".asc" signature, always
+// naming that exact key via --local-user so a CI keyring holding more than
+// one secret key can't cause us to sign with the wrong one.
+func signFile(dryRun bool, path, signerEnvVar string) error {
+	fingerprint, err := importKeyFromEnv(dryRun, signerEnvVar)
+	if err != nil {
+		return err
+	}
+	args := []string{"--batch", "--yes", "--detach-sign", "--armor"}
+	if fingerprint != "" {
+		args = append(args, "--local-user", fingerprint)
+	}
+	args = append(args, path)
+	return run(dryRun, "gpg", args...)
+}
+
+// importKeyFromEnv decodes the base64 PGP key stored in the environment
+// variable named envVar and imports it into gpg's keyring, returning the
+// fingerprint gpg assigned it (read off the --status-fd IMPORT_OK line) so
+// callers can address this exact key rather than whatever gpg would pick by
+// default.
+func importKeyFromEnv(dryRun bool, envVar string) (fingerprint string, err error) {
+	if dryRun {
+		return "", nil
+	}
+	encoded := os.Getenv(envVar)
+	if encoded == "" {
+		return "", fmt.Errorf("environment variable %s is not set", envVar)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", envVar, err)
+	}
+
+	cmd := exec.Command("gpg", "--batch", "--import", "--status-fd", "1")
+	cmd.Stdin = bytes.NewReader(key)
+	var status bytes.Buffer
+	cmd.Stdout = io.MultiWriter(os.Stdout, &status)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("importing key from %s: %w", envVar, err)
+	}
+
+	for _, line := range strings.Split(status.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "[GNUPG:]" && fields[1] == "IMPORT_OK" {
+			fingerprint = fields[len(fields)-1]
+		}
+	}
+	if fingerprint == "" {
+		return "", fmt.Errorf("could not determine fingerprint of key imported from %s", envVar)
+	}
+	return fingerprint, nil
+}
+
+// doImportKeys imports all signing keys CI needs, each a base64-encoded PGP
+// key stored in the named environment variable.
+func doImportKeys(cmdline []string) error {
+	fs := flag.NewFlagSet("importkeys", flag.ExitOnError)
+	dryRun := fs.Bool("n", false, "print the commands that would run, without running them")
+	fs.Parse(cmdline)
+
+	envVars := fs.Args()
+	if len(envVars) == 0 {
+		return fmt.Errorf("usage: go run build/ci.go importkeys <ENVVAR> [ENVVAR...]")
+	}
+	for _, envVar := range envVars {
+		if _, err := importKeyFromEnv(*dryRun, envVar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
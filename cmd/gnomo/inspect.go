@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tazmaan-defi/gnomo/internal/buildinfo"
+)
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	output := fs.String("output", "text", "output format: text|json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gnomo inspect [--output=text|json] <path-to-binary>")
+	}
+	path := fs.Arg(0)
+
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case "text":
+		fmt.Print(bi.String())
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bi)
+	default:
+		return fmt.Errorf("unknown output format %q (want text or json)", *output)
+	}
+	return nil
+}
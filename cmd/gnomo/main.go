@@ -2,16 +2,40 @@ package main
 
 import (
 	"fmt"
-	"runtime"
+	"os"
 )
 
+// Build-time metadata, injected via -ldflags (see build/ci.go). The names
+// are part of the build contract, so they stay in package main even though
+// internal/version.Get assembles the structured Info from them.
 var (
-	version = "dev"
-	commit  = "none"
-	date    = "unknown"
+	version    = "dev"
+	commit     = "none"
+	commitDate = ""
+	date       = "unknown"
+	treeState  = ""
 )
 
+var commands = map[string]func([]string) error{
+	"version": runVersion,
+	"inspect": runInspect,
+}
+
 func main() {
-	fmt.Printf("gnomo %s (%s) %s\n", version, commit, date)
-	fmt.Printf("go=%s os=%s arch=%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "gnomo %s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Println("usage: gnomo <command> [arguments]")
+	fmt.Println()
+	fmt.Println("commands:")
+	fmt.Println("  version    print gnomo build version information")
+	fmt.Println("  inspect    decode embedded build info from a Go binary")
+	os.Exit(2)
 }
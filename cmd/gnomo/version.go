@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	gnomoversion "github.com/tazmaan-defi/gnomo/internal/version"
+)
+
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	output := fs.String("output", "plain", "output format: plain|json|yaml|short")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	info := gnomoversion.Get(gnomoversion.Params{
+		Version:    version,
+		GitCommit:  commit,
+		CommitDate: commitDate,
+		BuildDate:  date,
+		TreeState:  treeState,
+	})
+
+	switch *output {
+	case "short":
+		fmt.Println(info.Version)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	case "yaml":
+		fmt.Print(info.YAML())
+	case "plain":
+		fmt.Print(info.String())
+	default:
+		return fmt.Errorf("unknown output format %q (want plain, json, yaml, or short)", *output)
+	}
+	return nil
+}
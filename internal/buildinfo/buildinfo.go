@@ -0,0 +1,324 @@
+// Package buildinfo extracts the Go toolchain version and module metadata
+// embedded in a Go-built executable, without relying on the binary being
+// the currently running process (unlike runtime/debug.ReadBuildInfo).
+//
+// The linker leaves a small "build info blob" inside every Go binary,
+// identified by a magic byte sequence, that records the Go version and a
+// pointer to the module info recorded by "go build"/"go install". This
+// package locates that blob inside ELF, Mach-O, and PE executables and
+// decodes it, which is what makes `gnomo inspect` work on any Go binary,
+// not just gnomo itself.
+package buildinfo
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+)
+
+// ErrNotGoExe is returned when the file is a recognized executable format
+// but does not contain a Go build info blob.
+var ErrNotGoExe = errors.New("not a Go executable")
+
+// ErrUnrecognizedFormat is returned when the file isn't a recognized ELF,
+// Mach-O, or PE executable.
+var ErrUnrecognizedFormat = errors.New("unrecognized executable format (want ELF, Mach-O, or PE)")
+
+// buildInfoMagic identifies the build info blob the Go linker embeds in
+// every binary. It is followed by one byte giving the pointer size (4 or
+// 8) and one byte of flags (bit 0: big-endian, bit 1: inline string data).
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+const (
+	buildInfoHeaderSize = 32 // magic(14) + ptrSize(1) + flags(1) + two pointers, padded for 32-bit
+	buildInfoAlign      = 16
+
+	// maxGoStringLen bounds how large a Go-version or module-info string we'll
+	// believe before reading it, so a corrupted or hostile binary can't make
+	// us allocate an unbounded buffer.
+	maxGoStringLen = 16 << 20 // 16MiB; real module info is at most a few KB
+)
+
+// ReadFile extracts build info from the Go executable at path.
+func ReadFile(path string) (*debug.BuildInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read extracts build info from a Go executable accessed through r.
+func Read(r io.ReaderAt) (*debug.BuildInfo, error) {
+	goVersion, modInfo, err := readRawBuildInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	bi, err := debug.ParseBuildInfo(modInfo)
+	if err != nil {
+		return nil, fmt.Errorf("parsing module info: %w", err)
+	}
+	bi.GoVersion = goVersion
+	return bi, nil
+}
+
+// binaryImage abstracts over ELF, Mach-O, and PE files enough to find and
+// read the build info blob, which may live in its own ".go.buildinfo"
+// section or, on platforms without one, near the start of the first
+// writable data segment.
+type binaryImage interface {
+	// ReadData reads up to size bytes starting at virtual address addr.
+	ReadData(addr, size uint64) ([]byte, error)
+	// DataStart returns the virtual address to start scanning for the
+	// build info blob.
+	DataStart() uint64
+}
+
+// readRawBuildInfo locates the build info blob in r and decodes its two
+// embedded strings: the Go version and the raw module info.
+func readRawBuildInfo(r io.ReaderAt) (goVersion, modInfo string, err error) {
+	ident := make([]byte, 16)
+	if n, err := r.ReadAt(ident, 0); n < len(ident) || err != nil {
+		return "", "", ErrUnrecognizedFormat
+	}
+
+	var img binaryImage
+	switch {
+	case bytes.HasPrefix(ident, []byte("\x7FELF")):
+		f, err := elf.NewFile(r)
+		if err != nil {
+			return "", "", ErrUnrecognizedFormat
+		}
+		img = &elfImage{f}
+	case bytes.HasPrefix(ident, []byte("MZ")):
+		f, err := pe.NewFile(r)
+		if err != nil {
+			return "", "", ErrUnrecognizedFormat
+		}
+		img = &peImage{f}
+	case bytes.HasPrefix(ident, []byte("\xFE\xED\xFA")) || bytes.HasPrefix(ident[1:], []byte("\xFA\xED\xFE")):
+		f, err := macho.NewFile(r)
+		if err != nil {
+			return "", "", ErrUnrecognizedFormat
+		}
+		img = &machoImage{f}
+	default:
+		return "", "", ErrUnrecognizedFormat
+	}
+
+	dataAddr := img.DataStart()
+	data, err := img.ReadData(dataAddr, 64*1024)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The blob is 16-byte aligned relative to dataAddr; scan for the magic
+	// prefix, skipping candidates that don't land on an aligned boundary
+	// (those are false positives inside unrelated data).
+	for {
+		i := bytes.Index(data, buildInfoMagic)
+		if i < 0 || len(data)-i < buildInfoHeaderSize {
+			return "", "", ErrNotGoExe
+		}
+		if i%buildInfoAlign == 0 {
+			data = data[i:]
+			break
+		}
+		data = data[(i+buildInfoAlign-1)&^(buildInfoAlign-1):]
+	}
+
+	ptrSize := int(data[14])
+	flags := data[15]
+	if flags&2 != 0 {
+		// Newer toolchains inline the strings directly after the header as
+		// varint-length-prefixed data instead of pointers.
+		goVersion, data = decodeVarintString(data[32:])
+		modInfo, _ = decodeVarintString(data)
+	} else {
+		bo := binary.ByteOrder(binary.LittleEndian)
+		if flags&1 != 0 {
+			bo = binary.BigEndian
+		}
+		readPtr, err := pointerReader(ptrSize, bo)
+		if err != nil {
+			return "", "", err
+		}
+		goVersion = readGoString(img, ptrSize, readPtr, readPtr(data[16:]))
+		modInfo = readGoString(img, ptrSize, readPtr, readPtr(data[16+ptrSize:]))
+	}
+	if goVersion == "" {
+		return "", "", ErrNotGoExe
+	}
+
+	// The module info string is wrapped in 16-byte sentinel markers
+	// (cmd/go/internal/modload's infoStart/infoEnd) that we strip here.
+	if len(modInfo) >= 33 && modInfo[len(modInfo)-17] == '\n' {
+		modInfo = modInfo[16 : len(modInfo)-16]
+	} else {
+		modInfo = ""
+	}
+	return goVersion, modInfo, nil
+}
+
+func pointerReader(ptrSize int, bo binary.ByteOrder) (func([]byte) uint64, error) {
+	switch ptrSize {
+	case 4:
+		return func(b []byte) uint64 { return uint64(bo.Uint32(b)) }, nil
+	case 8:
+		return bo.Uint64, nil
+	default:
+		return nil, ErrNotGoExe
+	}
+}
+
+// decodeVarintString reads a varint length followed by that many bytes of
+// string data, returning the string and the remaining data.
+func decodeVarintString(data []byte) (s string, rest []byte) {
+	n, nn := binary.Uvarint(data)
+	if nn <= 0 || n >= uint64(len(data)-nn) {
+		return "", nil
+	}
+	return string(data[nn : uint64(nn)+n]), data[uint64(nn)+n:]
+}
+
+// readGoString reads a Go string header (data pointer, length; both
+// ptrSize bytes) at addr, then returns the string it points to.
+func readGoString(img binaryImage, ptrSize int, readPtr func([]byte) uint64, addr uint64) string {
+	hdr, err := img.ReadData(addr, uint64(2*ptrSize))
+	if err != nil || len(hdr) < 2*ptrSize {
+		return ""
+	}
+	dataAddr := readPtr(hdr)
+	dataLen := readPtr(hdr[ptrSize:])
+	if dataLen > maxGoStringLen {
+		// Build info strings are at most a few KB in practice; a larger
+		// claimed length means either a corrupt binary or one crafted to
+		// make us allocate an enormous buffer.
+		return ""
+	}
+	data, err := img.ReadData(dataAddr, dataLen)
+	if err != nil || uint64(len(data)) < dataLen {
+		return ""
+	}
+	return string(data)
+}
+
+type elfImage struct{ f *elf.File }
+
+func (x *elfImage) ReadData(addr, size uint64) ([]byte, error) {
+	for _, prog := range x.f.Progs {
+		if prog.Vaddr <= addr && addr <= prog.Vaddr+prog.Filesz-1 {
+			n := prog.Vaddr + prog.Filesz - addr
+			if n > size {
+				n = size
+			}
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(io.NewSectionReader(prog, int64(addr-prog.Vaddr), int64(n)), buf); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		}
+	}
+	return nil, ErrUnrecognizedFormat
+}
+
+func (x *elfImage) DataStart() uint64 {
+	for _, s := range x.f.Sections {
+		if s.Name == ".go.buildinfo" {
+			return s.Addr
+		}
+	}
+	for _, p := range x.f.Progs {
+		if p.Type == elf.PT_LOAD && p.Flags&(elf.PF_X|elf.PF_W) == elf.PF_W {
+			return p.Vaddr
+		}
+	}
+	return 0
+}
+
+type peImage struct{ f *pe.File }
+
+func (x *peImage) imageBase() uint64 {
+	switch oh := x.f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(oh.ImageBase)
+	case *pe.OptionalHeader64:
+		return oh.ImageBase
+	}
+	return 0
+}
+
+func (x *peImage) ReadData(addr, size uint64) ([]byte, error) {
+	addr -= x.imageBase()
+	for _, sect := range x.f.Sections {
+		if uint64(sect.VirtualAddress) <= addr && addr <= uint64(sect.VirtualAddress+sect.Size-1) {
+			n := uint64(sect.VirtualAddress+sect.Size) - addr
+			if n > size {
+				n = size
+			}
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(io.NewSectionReader(sect, int64(addr-uint64(sect.VirtualAddress)), int64(n)), buf); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		}
+	}
+	return nil, ErrUnrecognizedFormat
+}
+
+func (x *peImage) DataStart() uint64 {
+	const dataCharacteristics = 0x00000040 | 0x40000000 | 0x80000000 // IMAGE_SCN_CNT_INITIALIZED_DATA | MEM_READ | MEM_WRITE
+	for _, sect := range x.f.Sections {
+		if sect.VirtualAddress != 0 && sect.Size != 0 &&
+			sect.Characteristics&^uint32(0x600000) == dataCharacteristics {
+			return uint64(sect.VirtualAddress) + x.imageBase()
+		}
+	}
+	return 0
+}
+
+type machoImage struct{ f *macho.File }
+
+func (x *machoImage) ReadData(addr, size uint64) ([]byte, error) {
+	for _, load := range x.f.Loads {
+		seg, ok := load.(*macho.Segment)
+		if !ok || seg.Name == "__PAGEZERO" {
+			continue
+		}
+		if seg.Addr <= addr && addr <= seg.Addr+seg.Filesz-1 {
+			n := seg.Addr + seg.Filesz - addr
+			if n > size {
+				n = size
+			}
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(io.NewSectionReader(seg, int64(addr-seg.Addr), int64(n)), buf); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		}
+	}
+	return nil, ErrUnrecognizedFormat
+}
+
+func (x *machoImage) DataStart() uint64 {
+	for _, sec := range x.f.Sections {
+		if sec.Name == "__go_buildinfo" {
+			return sec.Addr
+		}
+	}
+	const protRW = 3 // VM_PROT_READ | VM_PROT_WRITE
+	for _, load := range x.f.Loads {
+		if seg, ok := load.(*macho.Segment); ok && seg.Addr != 0 && seg.Filesz != 0 && seg.Prot == protRW && seg.Maxprot == protRW {
+			return seg.Addr
+		}
+	}
+	return 0
+}
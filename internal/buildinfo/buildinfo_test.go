@@ -0,0 +1,115 @@
+package buildinfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// buildTestBinary compiles pkg (an import path within this module) to a
+// temporary executable and returns its path.
+func buildTestBinary(t *testing.T, pkg string) string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found on PATH")
+	}
+
+	out := filepath.Join(t.TempDir(), "testbin")
+	if runtime.GOOS == "windows" {
+		out += ".exe"
+	}
+	cmd := exec.Command(goBin, "build", "-o", out, pkg)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building %s: %v\n%s", pkg, err, output)
+	}
+	return out
+}
+
+func TestReadFile_GoBinary(t *testing.T) {
+	path := buildTestBinary(t, "github.com/tazmaan-defi/gnomo/cmd/gnomo")
+
+	bi, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+
+	if bi.GoVersion == "" {
+		t.Error("GoVersion is empty")
+	}
+	const wantPath = "github.com/tazmaan-defi/gnomo/cmd/gnomo"
+	if bi.Path != wantPath {
+		t.Errorf("Path = %q, want %q", bi.Path, wantPath)
+	}
+	if bi.Main.Path != "github.com/tazmaan-defi/gnomo" {
+		t.Errorf("Main.Path = %q, want module path", bi.Main.Path)
+	}
+
+	var gotRevision, gotModified bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			gotRevision = s.Value != ""
+		case "vcs.modified":
+			gotModified = true
+		}
+	}
+	if !gotRevision {
+		t.Error("no non-empty vcs.revision setting (expected since this module is a git checkout)")
+	}
+	if !gotModified {
+		t.Error("no vcs.modified setting")
+	}
+}
+
+func TestReadFile_NotGoExecutable(t *testing.T) {
+	candidates := []string{"/bin/ls", "/usr/bin/ls", "/bin/sh"}
+	var path string
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			path = c
+			break
+		}
+	}
+	if path == "" {
+		t.Skip("no system ELF binary found to test against")
+	}
+
+	_, err := ReadFile(path)
+	if err != ErrNotGoExe {
+		t.Errorf("ReadFile(%q) error = %v, want %v", path, err, ErrNotGoExe)
+	}
+}
+
+func TestReadFile_UnrecognizedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-binary")
+	if err := os.WriteFile(path, []byte("this is plain text, not an executable\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadFile(path)
+	if err != ErrUnrecognizedFormat {
+		t.Errorf("ReadFile(%q) error = %v, want %v", path, err, ErrUnrecognizedFormat)
+	}
+}
+
+func TestReadFile_Truncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "truncated")
+	if err := os.WriteFile(path, []byte("\x7FELF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadFile(path)
+	if err != ErrUnrecognizedFormat {
+		t.Errorf("ReadFile(%q) error = %v, want %v", path, err, ErrUnrecognizedFormat)
+	}
+}
+
+func TestReadFile_MissingFile(t *testing.T) {
+	_, err := ReadFile(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err == nil {
+		t.Error("expected an error for a missing file, got nil")
+	}
+}
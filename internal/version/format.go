@@ -0,0 +1,43 @@
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String renders Info in the same plain, human-readable form as gnomo's
+// banner output.
+func (i Info) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gnomo %s\n", i.Version)
+	fmt.Fprintf(&b, "  git commit:  %s\n", i.GitCommit)
+	if i.CommitDate != "" {
+		fmt.Fprintf(&b, "  commit date: %s\n", i.CommitDate)
+	}
+	fmt.Fprintf(&b, "  build date:  %s\n", i.BuildDate)
+	fmt.Fprintf(&b, "  dirty build: %t\n", i.DirtyBuild)
+	fmt.Fprintf(&b, "  go version:  %s\n", i.GoVersion)
+	fmt.Fprintf(&b, "  compiler:    %s\n", i.Compiler)
+	fmt.Fprintf(&b, "  platform:    %s\n", i.Platform)
+	if i.Meta != "" {
+		fmt.Fprintf(&b, "  meta:        %s\n", i.Meta)
+	}
+	return b.String()
+}
+
+// YAML renders Info as a flat YAML document. Info has no nested or
+// slice-valued fields, so a hand-rolled encoder avoids pulling in a YAML
+// dependency just for this.
+func (i Info) YAML() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version: %s\n", i.Version)
+	fmt.Fprintf(&b, "gitCommit: %s\n", i.GitCommit)
+	fmt.Fprintf(&b, "commitDate: %s\n", i.CommitDate)
+	fmt.Fprintf(&b, "buildDate: %s\n", i.BuildDate)
+	fmt.Fprintf(&b, "dirtyBuild: %t\n", i.DirtyBuild)
+	fmt.Fprintf(&b, "goVersion: %s\n", i.GoVersion)
+	fmt.Fprintf(&b, "compiler: %s\n", i.Compiler)
+	fmt.Fprintf(&b, "platform: %s\n", i.Platform)
+	fmt.Fprintf(&b, "meta: %s\n", i.Meta)
+	return b.String()
+}
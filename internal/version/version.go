@@ -0,0 +1,86 @@
+// Package version exposes structured build and version metadata for gnomo.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Info describes the provenance of the running gnomo binary.
+type Info struct {
+	Version    string
+	GitCommit  string
+	CommitDate string
+	BuildDate  string
+	DirtyBuild bool
+	GoVersion  string
+	Compiler   string
+	Platform   string
+	Meta       string
+}
+
+// Params carries the values normally injected at build time via -ldflags
+// (see build/ci.go). Any field left empty is filled in from the binary's
+// embedded build info instead.
+type Params struct {
+	Version    string
+	GitCommit  string
+	CommitDate string
+	BuildDate  string
+	// TreeState is "clean" or "dirty", as stamped by `go run build/ci.go
+	// release`. Left empty for builds that don't set it (e.g. `go install`),
+	// in which case DirtyBuild falls back to the embedded vcs.modified flag.
+	TreeState string
+}
+
+// Get assembles Info from p, falling back to the Go module's embedded VCS
+// metadata (via runtime/debug.ReadBuildInfo) for anything p doesn't supply.
+// This keeps `go install` builds, which never see our -ldflags, reporting a
+// real commit and build state instead of the "dev/none/unknown" defaults.
+func Get(p Params) Info {
+	info := Info{
+		Version:    p.Version,
+		GitCommit:  p.GitCommit,
+		CommitDate: p.CommitDate,
+		BuildDate:  p.BuildDate,
+		GoVersion:  runtime.Version(),
+		Compiler:   runtime.Compiler,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	switch p.TreeState {
+	case "dirty":
+		info.DirtyBuild = true
+	case "clean":
+		info.DirtyBuild = false
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if info.Version == "" || info.Version == "dev" {
+		if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.GitCommit == "" || info.GitCommit == "none" {
+				info.GitCommit = s.Value
+			}
+		case "vcs.time":
+			if info.CommitDate == "" {
+				info.CommitDate = s.Value
+			}
+		case "vcs.modified":
+			if p.TreeState == "" {
+				info.DirtyBuild = s.Value == "true"
+			}
+		}
+	}
+
+	return info
+}